@@ -2,16 +2,29 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/alecthomas/chroma/quick"
+	"github.com/fsnotify/fsnotify"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/grafana/cortextool/pkg/client"
 	"github.com/grafana/cortextool/pkg/rules"
+	"github.com/grafana/cortextool/pkg/rules/lint"
+	"github.com/grafana/cortextool/pkg/rules/store"
+	ruletest "github.com/grafana/cortextool/pkg/rules/test"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/alecthomas/kingpin.v2"
 	"gopkg.in/yaml.v2"
@@ -28,13 +41,70 @@ var (
 		Name:      "last_rule_load_success_timestamp_seconds",
 		Help:      "The timestamp of the last successful rule load.",
 	})
+	ruleLoadCreatedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cortex",
+		Name:      "rule_load_created_total",
+		Help:      "Number of rule groups created by a load or sync operation.",
+	}, []string{"namespace", "group"})
+	ruleLoadUpdatedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cortex",
+		Name:      "rule_load_updated_total",
+		Help:      "Number of rule groups updated by a load or sync operation.",
+	}, []string{"namespace", "group"})
+	ruleLoadDeletedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cortex",
+		Name:      "rule_load_deleted_total",
+		Help:      "Number of rule groups deleted by a sync operation.",
+	}, []string{"namespace", "group"})
+	ruleLoadUnchangedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cortex",
+		Name:      "rule_load_unchanged_total",
+		Help:      "Number of rule groups left unchanged by a load or sync operation.",
+	}, []string{"namespace", "group"})
+	ruleLoadErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cortex",
+		Name:      "rule_load_errors_total",
+		Help:      "Number of errors encountered while loading or syncing a rule group.",
+	}, []string{"namespace", "group"})
+	ruleLoadDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "cortex",
+		Name:      "rule_load_duration_seconds",
+		Help:      "Time taken to complete a single load or sync operation.",
+	})
 )
 
+// recordRuleLoadAction increments the counter matching the outcome of
+// creating, updating, deleting or leaving unchanged a single rule group.
+func recordRuleLoadAction(namespace, group string, action syncAction) {
+	switch action {
+	case syncActionCreate:
+		ruleLoadCreatedTotal.WithLabelValues(namespace, group).Inc()
+	case syncActionUpdate:
+		ruleLoadUpdatedTotal.WithLabelValues(namespace, group).Inc()
+	case syncActionDelete:
+		ruleLoadDeletedTotal.WithLabelValues(namespace, group).Inc()
+	case syncActionUnchanged:
+		ruleLoadUnchangedTotal.WithLabelValues(namespace, group).Inc()
+	}
+}
+
 // RuleCommand configures and executes rule related cortex api operations
 type RuleCommand struct {
 	ClientConfig client.Config
 
-	cli *client.CortexClient
+	cli   *client.CortexClient
+	store store.RuleStore
+
+	// Backend Configs
+	Backend                 string
+	LocalRuleDir            string
+	ObjectStorageConfigFile string
+
+	// Multi-tenant Configs
+	IDFile          string
+	AllTenants      bool
+	tenantOverrides map[string]tenantOverride
+	storesByTenant  map[string]store.RuleStore
 
 	// Get Rule Groups Configs
 	Namespace string
@@ -42,14 +112,42 @@ type RuleCommand struct {
 
 	// Load Rules Configs
 	RuleFiles []string
+
+	// Sync Rules Configs
+	Namespaces      []string
+	NamespacePrefix string
+	DryRun          bool
+	Concurrency     int
+
+	// Lint Rules Configs
+	RequireLabels    []string
+	MaxSubqueryDepth int
+	LintFormat       string
+
+	// Test Rules Configs
+	TestFiles []string
+
+	// Watch Configs
+	Watch bool
+
+	// Observability Configs
+	MetricsListenAddress string
+	LogFormat            string
 }
 
 // Register rule related commands and flags with the kingpin application
 func (r *RuleCommand) Register(app *kingpin.Application) {
 	rulesCmd := app.Command("rules", "View & edit rules stored in cortex.").PreAction(r.setup)
-	rulesCmd.Flag("address", "Address of the cortex cluster, alternatively set CORTEX_ADDRESS.").Envar("CORTEX_ADDRESS").Required().StringVar(&r.ClientConfig.Address)
-	rulesCmd.Flag("id", "Cortex tenant id, alternatively set CORTEX_TENTANT_ID.").Envar("CORTEX_TENTANT_ID").Required().StringVar(&r.ClientConfig.ID)
+	rulesCmd.Flag("address", "Address of the cortex cluster, alternatively set CORTEX_ADDRESS. Required when --backend=cortex.").Envar("CORTEX_ADDRESS").StringVar(&r.ClientConfig.Address)
+	rulesCmd.Flag("id", "Cortex tenant id, alternatively set CORTEX_TENTANT_ID. Not required when --id-file is used.").Envar("CORTEX_TENTANT_ID").StringVar(&r.ClientConfig.ID)
 	rulesCmd.Flag("key", "Api key to use when contacting cortex, alternatively set $CORTEX_API_KEY.").Default("").Envar("CORTEX_API_KEY").StringVar(&r.ClientConfig.Key)
+	rulesCmd.Flag("backend", "Backend to read/write rules from: cortex, local or object-storage.").Default(string(store.BackendCortex)).StringVar(&r.Backend)
+	rulesCmd.Flag("local-rule-dir", "Directory to read/write rules from when --backend=local.").StringVar(&r.LocalRuleDir)
+	rulesCmd.Flag("object-storage-config-file", "Path to a thanos-style bucket config YAML file, used when --backend=object-storage.").StringVar(&r.ObjectStorageConfigFile)
+	rulesCmd.Flag("id-file", "Path to a YAML file mapping tenant IDs to per-tenant address/key overrides, for operating across multiple tenants in one invocation.").ExistingFileVar(&r.IDFile)
+	rulesCmd.Flag("all-tenants", "Enumerate every known tenant instead of just --id. Only applies to list and print.").BoolVar(&r.AllTenants)
+	rulesCmd.Flag("metrics-listen-address", "Address to serve Prometheus metrics on at /metrics. If not set, no metrics server is started.").StringVar(&r.MetricsListenAddress)
+	rulesCmd.Flag("log-format", "Log format to use: logfmt or json.").Default("logfmt").StringVar(&r.LogFormat)
 
 	// List Rules Command
 	rulesCmd.Command("list", "List the rules currently in the cortex ruler.").Action(r.listRules)
@@ -69,36 +167,238 @@ func (r *RuleCommand) Register(app *kingpin.Application) {
 
 	loadRulesCmd := rulesCmd.Command("load", "load a set of rules to a designated cortex endpoint").Action(r.loadRules)
 	loadRulesCmd.Arg("rule-files", "The rule files to check.").Required().ExistingFilesVar(&r.RuleFiles)
+	loadRulesCmd.Flag("watch", "Re-run the load whenever one of the rule files changes, instead of exiting.").BoolVar(&r.Watch)
+
+	// Sync Rules Command
+	syncRulesCmd := rulesCmd.Command("sync", "sync a set of rules to a designated cortex endpoint, pruning remote groups that no longer exist locally").Action(r.syncRules)
+	syncRulesCmd.Arg("rule-files", "The rule files to load and sync.").Required().ExistingFilesVar(&r.RuleFiles)
+	syncRulesCmd.Flag("namespaces", "Namespace to scope the sync to, may be repeated. If not set, every namespace is synced, and remote namespaces with no local rule file have all their groups deleted.").StringsVar(&r.Namespaces)
+	syncRulesCmd.Flag("namespace-prefix", "Only sync namespaces with this prefix.").StringVar(&r.NamespacePrefix)
+	syncRulesCmd.Flag("dry-run", "Print the changes that would be made without applying them; existing rule groups are still read from the rule store.").BoolVar(&r.DryRun)
+	syncRulesCmd.Flag("concurrency", "Number of rule groups to sync concurrently.").Default("8").IntVar(&r.Concurrency)
+	syncRulesCmd.Flag("watch", "Re-run the sync whenever one of the rule files changes, instead of exiting.").BoolVar(&r.Watch)
+
+	// Lint Rules Command
+	lintRulesCmd := rulesCmd.Command("lint", "lint local rule files for PromQL errors, label policy violations and common anti-patterns").Action(r.lintRules)
+	lintRulesCmd.Arg("rule-files", "The rule files to lint.").Required().ExistingFilesVar(&r.RuleFiles)
+	lintRulesCmd.Flag("require-label", "Require alerting rules to set label=value1|value2|..., may be repeated.").StringsVar(&r.RequireLabels)
+	lintRulesCmd.Flag("max-subquery-depth", "Warn when subqueries are nested deeper than this. 0 disables the check.").Default("2").IntVar(&r.MaxSubqueryDepth)
+	lintRulesCmd.Flag("format", "Output format: text or json.").Default("text").StringVar(&r.LintFormat)
+
+	// Test Rules Command
+	testRulesCmd := rulesCmd.Command("test", "run promtool-style unit tests against local rule files").Action(r.testRules)
+	testRulesCmd.Arg("test-files", "The test files to run, in the same schema as `promtool test rules`.").Required().ExistingFilesVar(&r.TestFiles)
+}
+
+// tenantOverride customizes the cortex connection details for a single
+// tenant when operating against --id-file.
+type tenantOverride struct {
+	Address string `yaml:"address"`
+	Key     string `yaml:"key"`
 }
 
 func (r *RuleCommand) setup(k *kingpin.ParseContext) error {
+	switch r.LogFormat {
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	case "logfmt", "":
+		log.SetFormatter(&log.TextFormatter{DisableColors: true})
+	default:
+		return fmt.Errorf("invalid --log-format %q, must be logfmt or json", r.LogFormat)
+	}
+
 	prometheus.MustRegister(
 		ruleLoadTimestamp,
 		ruleLoadSuccessTimestamp,
+		ruleLoadCreatedTotal,
+		ruleLoadUpdatedTotal,
+		ruleLoadDeletedTotal,
+		ruleLoadUnchangedTotal,
+		ruleLoadErrorsTotal,
+		ruleLoadDuration,
 	)
 
-	cli, err := client.New(r.ClientConfig)
-	if err != nil {
-		return err
+	if r.MetricsListenAddress != "" {
+		ln, err := net.Listen("tcp", r.MetricsListenAddress)
+		if err != nil {
+			return errors.Wrap(err, "unable to start metrics listener")
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		go func() {
+			if err := http.Serve(ln, mux); err != nil {
+				log.WithError(err).Error("metrics server stopped")
+			}
+		}()
+	}
+
+	// lint and test operate entirely on local rule/test files; they need
+	// neither a tenant ID nor a backend store, so skip that setup rather
+	// than demanding --id/--address for commands that never contact one.
+	if k.SelectedCommand != nil {
+		switch k.SelectedCommand.FullCommand() {
+		case "rules lint", "rules test":
+			return nil
+		}
+	}
+
+	r.storesByTenant = map[string]store.RuleStore{}
+
+	if r.IDFile != "" {
+		d, err := ioutil.ReadFile(r.IDFile)
+		if err != nil {
+			return errors.Wrap(err, "unable to read --id-file")
+		}
+		if err := yaml.Unmarshal(d, &r.tenantOverrides); err != nil {
+			return errors.Wrap(err, "unable to parse --id-file")
+		}
+	}
+
+	if r.ClientConfig.ID == "" && r.IDFile == "" {
+		return errors.New("--id or --id-file is required")
+	}
+
+	isCortexBackend := store.Backend(r.Backend) == store.BackendCortex || store.Backend(r.Backend) == ""
+	if isCortexBackend && r.ClientConfig.Address == "" {
+		return errors.New("--address is required when --backend=cortex")
+	}
+
+	if !isCortexBackend || r.ClientConfig.ID != "" {
+		if isCortexBackend {
+			cli, err := client.New(r.ClientConfig)
+			if err != nil {
+				return err
+			}
+			r.cli = cli
+		}
+
+		s, err := store.NewRuleStore(store.Config{
+			Backend:                 store.Backend(r.Backend),
+			LocalDirectory:          r.LocalRuleDir,
+			ObjectStorageConfigFile: r.ObjectStorageConfigFile,
+		}, r.cli)
+		if err != nil {
+			return errors.Wrap(err, "unable to initialize rule store")
+		}
+		r.store = s
 	}
-	r.cli = cli
 
 	return nil
 }
 
-func (r *RuleCommand) listRules(k *kingpin.ParseContext) error {
-	rules, err := r.cli.ListRules(context.Background(), "")
+// storeForTenant returns the RuleStore to use for tenantID. For non-cortex
+// backends the tenant is just a parameter to an already-constructed store;
+// for the cortex backend each tenant needs its own authenticated client,
+// built lazily and cached.
+func (r *RuleCommand) storeForTenant(tenantID string) (store.RuleStore, error) {
+	isCortexBackend := store.Backend(r.Backend) == store.BackendCortex || store.Backend(r.Backend) == ""
+	if !isCortexBackend {
+		return r.store, nil
+	}
+
+	if tenantID == r.ClientConfig.ID && r.store != nil {
+		return r.store, nil
+	}
+	if s, ok := r.storesByTenant[tenantID]; ok {
+		return s, nil
+	}
+
+	cfg := r.ClientConfig
+	cfg.ID = tenantID
+	if override, ok := r.tenantOverrides[tenantID]; ok {
+		if override.Address != "" {
+			cfg.Address = override.Address
+		}
+		if override.Key != "" {
+			cfg.Key = override.Key
+		}
+	}
+
+	cli, err := client.New(cfg)
 	if err != nil {
-		log.Fatalf("unable to read rules from cortex, %v", err)
+		return nil, errors.Wrapf(err, "unable to create cortex client for tenant %s", tenantID)
+	}
+
+	s := store.NewCortexStore(cli)
+	r.storesByTenant[tenantID] = s
+	return s, nil
+}
+
+// tenantsToProcess returns the tenant IDs a list/print invocation should
+// enumerate: just --id, unless --all-tenants was given, in which case it is
+// every tenant named in --id-file, or every tenant the store itself knows
+// about if no --id-file was supplied.
+func (r *RuleCommand) tenantsToProcess(ctx context.Context) ([]string, error) {
+	if !r.AllTenants {
+		return []string{r.ClientConfig.ID}, nil
+	}
+
+	if len(r.tenantOverrides) > 0 {
+		tenants := make([]string, 0, len(r.tenantOverrides))
+		for id := range r.tenantOverrides {
+			tenants = append(tenants, id)
+		}
+		sort.Strings(tenants)
+		return tenants, nil
+	}
+
+	return r.store.ListAllUsers(ctx)
+}
+
+// namespaceTenants reads the optional top-level `tenant:` field out of each
+// rule file, keyed by namespace the same way rules.ParseFiles derives it: the
+// file's explicit `namespace:` field if set, otherwise its base name with the
+// extension stripped.
+func namespaceTenants(files []string) (map[string]string, error) {
+	tenants := map[string]string{}
+	for _, f := range files {
+		d, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read rule file %s", f)
+		}
+
+		var wrapper struct {
+			Namespace string `yaml:"namespace"`
+			Tenant    string `yaml:"tenant"`
+		}
+		if err := yaml.Unmarshal(d, &wrapper); err != nil {
+			return nil, errors.Wrapf(err, "unable to parse rule file %s", f)
+		}
+		if wrapper.Tenant == "" {
+			continue
+		}
+
+		namespace := wrapper.Namespace
+		if namespace == "" {
+			base := filepath.Base(f)
+			namespace = strings.TrimSuffix(base, filepath.Ext(base))
+		}
+		tenants[namespace] = wrapper.Tenant
+	}
+	return tenants, nil
+}
 
+func (r *RuleCommand) listRules(k *kingpin.ParseContext) error {
+	ctx := context.Background()
+	tenants, err := r.tenantsToProcess(ctx)
+	if err != nil {
+		log.Fatalf("unable to determine tenants to list, %v", err)
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
 
-	fmt.Fprintln(w, "Namespace\t Rule Group")
-	for ns, rulegroups := range rules {
-		for _, rg := range rulegroups {
-			fmt.Fprintf(w, "%s\t %s\n", ns, rg.Name)
+	fmt.Fprintln(w, "Tenant\t Namespace\t Rule Group")
+	for _, tenant := range tenants {
+		s, err := r.storeForTenant(tenant)
+		if err != nil {
+			log.Fatalf("unable to read rules, %v", err)
+		}
+		groups, err := s.ListRuleGroupsForUserAndNamespace(ctx, tenant, "")
+		if err != nil {
+			log.Fatalf("unable to read rules, %v", err)
+		}
+		for _, rg := range groups {
+			fmt.Fprintf(w, "%s\t %s\t %s\n", tenant, rg.Namespace, rg.Name)
 		}
 	}
 
@@ -108,15 +408,36 @@ func (r *RuleCommand) listRules(k *kingpin.ParseContext) error {
 }
 
 func (r *RuleCommand) printRules(k *kingpin.ParseContext) error {
-	rules, err := r.cli.ListRules(context.Background(), "")
+	ctx := context.Background()
+	tenants, err := r.tenantsToProcess(ctx)
 	if err != nil {
-		if err == client.ErrResourceNotFound {
-			log.Infof("no rule groups currently exist for this user")
-			return nil
+		log.Fatalf("unable to determine tenants to print, %v", err)
+	}
+
+	byTenant := map[string]map[string][]rules.RuleGroup{}
+	for _, tenant := range tenants {
+		s, err := r.storeForTenant(tenant)
+		if err != nil {
+			log.Fatalf("unable to read rules, %v", err)
+		}
+		groups, err := s.ListRuleGroupsForUserAndNamespace(ctx, tenant, "")
+		if err != nil {
+			log.Fatalf("unable to read rules, %v", err)
+		}
+		for _, rg := range groups {
+			if byTenant[tenant] == nil {
+				byTenant[tenant] = map[string][]rules.RuleGroup{}
+			}
+			byTenant[tenant][rg.Namespace] = append(byTenant[tenant][rg.Namespace], rg.RuleGroup)
 		}
-		log.Fatalf("unable to read rules from cortex, %v", err)
 	}
-	d, err := yaml.Marshal(&rules)
+
+	if len(byTenant) == 0 {
+		log.Infof("no rule groups currently exist for this user")
+		return nil
+	}
+
+	d, err := yaml.Marshal(&byTenant)
 	if err != nil {
 		return err
 	}
@@ -130,13 +451,21 @@ func (r *RuleCommand) printRules(k *kingpin.ParseContext) error {
 }
 
 func (r *RuleCommand) getRuleGroup(k *kingpin.ParseContext) error {
-	group, err := r.cli.GetRuleGroup(context.Background(), r.Namespace, r.RuleGroup)
+	if r.ClientConfig.ID == "" {
+		return errors.New("--id is required for get")
+	}
+	s, err := r.storeForTenant(r.ClientConfig.ID)
+	if err != nil {
+		log.Fatalf("unable to read rules, %v", err)
+	}
+
+	group, err := s.GetRuleGroup(context.Background(), r.ClientConfig.ID, r.Namespace, r.RuleGroup)
 	if err != nil {
-		if err == client.ErrResourceNotFound {
+		if err == store.ErrGroupNotFound {
 			log.Infof("this rule group does not currently exist")
 			return nil
 		}
-		log.Fatalf("unable to read rules from cortex, %v", err)
+		log.Fatalf("unable to read rules, %v", err)
 	}
 	d, err := yaml.Marshal(&group)
 	if err != nil {
@@ -152,53 +481,432 @@ func (r *RuleCommand) getRuleGroup(k *kingpin.ParseContext) error {
 }
 
 func (r *RuleCommand) deleteRuleGroup(k *kingpin.ParseContext) error {
-	err := r.cli.DeleteRuleGroup(context.Background(), r.Namespace, r.RuleGroup)
+	if r.ClientConfig.ID == "" {
+		return errors.New("--id is required for delete")
+	}
+	s, err := r.storeForTenant(r.ClientConfig.ID)
+	if err != nil {
+		log.Fatalf("unable to delete rule group, %v", err)
+	}
+
+	err = s.DeleteRuleGroup(context.Background(), r.ClientConfig.ID, r.Namespace, r.RuleGroup)
 	if err != nil {
-		log.Fatalf("unable to delete rule group from cortex, %v", err)
+		log.Fatalf("unable to delete rule group, %v", err)
 	}
 	return nil
 }
 
 func (r *RuleCommand) loadRules(k *kingpin.ParseContext) error {
+	return r.watch(r.RuleFiles, r.loadRulesOnce)
+}
+
+func (r *RuleCommand) loadRulesOnce() error {
+	timer := prometheus.NewTimer(ruleLoadDuration)
+	defer timer.ObserveDuration()
+
 	nss, err := rules.ParseFiles(r.RuleFiles)
 	if err != nil {
 		return errors.Wrap(err, "load operation unsuccessful, unable to parse rules files")
 	}
+	tenants, err := namespaceTenants(r.RuleFiles)
+	if err != nil {
+		return errors.Wrap(err, "load operation unsuccessful, unable to read tenant overrides")
+	}
 	ruleLoadTimestamp.SetToCurrentTime()
 
 	for _, ns := range nss {
+		tenantID := r.ClientConfig.ID
+		if t, ok := tenants[ns.Namespace]; ok {
+			tenantID = t
+		}
+		s, err := r.storeForTenant(tenantID)
+		if err != nil {
+			return errors.Wrap(err, "load operation unsuccessful")
+		}
+
 		for _, group := range ns.Groups {
-			curGroup, err := r.cli.GetRuleGroup(context.Background(), ns.Namespace, group.Name)
-			if err != nil && err != client.ErrResourceNotFound {
-				return errors.Wrap(err, "load operation unsuccessful, unable to contact cortex api")
+			curGroup, err := s.GetRuleGroup(context.Background(), tenantID, ns.Namespace, group.Name)
+			if err != nil && err != store.ErrGroupNotFound {
+				ruleLoadErrorsTotal.WithLabelValues(ns.Namespace, group.Name).Inc()
+				return errors.Wrap(err, "load operation unsuccessful, unable to contact rule store")
 			}
+
+			action := syncActionCreate
 			if curGroup != nil {
+				action = syncActionUpdate
 				err = rules.CompareGroups(*curGroup, group)
 				if err == nil {
 					log.WithFields(log.Fields{
+						"tenant":    tenantID,
 						"group":     group.Name,
 						"namespace": ns.Namespace,
 					}).Infof("group already exists")
+					recordRuleLoadAction(ns.Namespace, group.Name, syncActionUnchanged)
 					continue
 				}
 				log.WithFields(log.Fields{
+					"tenant":     tenantID,
 					"group":      group.Name,
 					"namespace":  ns.Namespace,
 					"difference": err,
 				}).Infof("updating group")
 			}
 
-			err = r.cli.CreateRuleGroup(context.Background(), ns.Namespace, group)
+			err = s.SetRuleGroup(context.Background(), tenantID, ns.Namespace, group)
 			if err != nil {
+				ruleLoadErrorsTotal.WithLabelValues(ns.Namespace, group.Name).Inc()
 				log.WithError(err).WithFields(log.Fields{
+					"tenant":    tenantID,
 					"group":     group.Name,
 					"namespace": ns.Namespace,
 				}).Errorf("unable to load rule group")
 				return fmt.Errorf("load operation unsuccessful")
 			}
+			recordRuleLoadAction(ns.Namespace, group.Name, action)
+		}
+	}
+
+	ruleLoadSuccessTimestamp.SetToCurrentTime()
+	return nil
+}
+
+// watch runs fn once, then, if --watch was set, re-runs fn whenever one of
+// files changes, until the watcher is closed by process exit. Errors from
+// re-runs are logged rather than returned, since there is no caller left to
+// return them to.
+func (r *RuleCommand) watch(files []string, fn func() error) error {
+	if err := fn(); err != nil {
+		return err
+	}
+	if !r.Watch {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "unable to start file watcher")
+	}
+	defer watcher.Close()
+
+	dirs := map[string]struct{}{}
+	for _, f := range files {
+		dirs[filepath.Dir(f)] = struct{}{}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return errors.Wrapf(err, "unable to watch %s", dir)
+		}
+	}
+
+	log.Infof("watching %d director(y/ies) for rule file changes", len(dirs))
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			log.WithField("file", event.Name).Infof("rule file changed, re-running")
+			if err := fn(); err != nil {
+				log.WithError(err).Error("re-run failed")
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.WithError(err).Error("file watcher error")
 		}
 	}
+}
+
+// syncAction describes what syncRules has decided to do with a rule group.
+type syncAction int
+
+const (
+	syncActionCreate syncAction = iota
+	syncActionUpdate
+	syncActionDelete
+	syncActionUnchanged
+)
+
+func (a syncAction) String() string {
+	switch a {
+	case syncActionCreate:
+		return "create"
+	case syncActionUpdate:
+		return "update"
+	case syncActionDelete:
+		return "delete"
+	default:
+		return "unchanged"
+	}
+}
+
+// syncChange is a single planned change to a namespace/group pair.
+type syncChange struct {
+	Tenant    string
+	Namespace string
+	Group     rules.RuleGroup
+	Action    syncAction
+}
+
+// inScope reports whether a namespace should be considered by the sync,
+// honouring --namespaces and --namespace-prefix.
+func (r *RuleCommand) inScope(namespace string) bool {
+	if r.NamespacePrefix != "" && !strings.HasPrefix(namespace, r.NamespacePrefix) {
+		return false
+	}
+	if len(r.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range r.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RuleCommand) syncRules(k *kingpin.ParseContext) error {
+	return r.watch(r.RuleFiles, r.syncRulesOnce)
+}
+
+func (r *RuleCommand) syncRulesOnce() error {
+	timer := prometheus.NewTimer(ruleLoadDuration)
+	defer timer.ObserveDuration()
+
+	nss, err := rules.ParseFiles(r.RuleFiles)
+	if err != nil {
+		return errors.Wrap(err, "sync operation unsuccessful, unable to parse rules files")
+	}
+	nsTenants, err := namespaceTenants(r.RuleFiles)
+	if err != nil {
+		return errors.Wrap(err, "sync operation unsuccessful, unable to read tenant overrides")
+	}
+	ruleLoadTimestamp.SetToCurrentTime()
+
+	// local[tenant][namespace][group]
+	local := map[string]map[string]map[string]rules.RuleGroup{}
+	for _, ns := range nss {
+		if !r.inScope(ns.Namespace) {
+			continue
+		}
+		tenantID := r.ClientConfig.ID
+		if t, ok := nsTenants[ns.Namespace]; ok {
+			tenantID = t
+		}
+
+		groups := make(map[string]rules.RuleGroup, len(ns.Groups))
+		for _, group := range ns.Groups {
+			groups[group.Name] = group
+		}
+		if local[tenantID] == nil {
+			local[tenantID] = map[string]map[string]rules.RuleGroup{}
+		}
+		local[tenantID][ns.Namespace] = groups
+	}
+
+	changes := []syncChange{}
+	for tenantID, tenantNamespaces := range local {
+		s, err := r.storeForTenant(tenantID)
+		if err != nil {
+			return errors.Wrap(err, "sync operation unsuccessful")
+		}
+
+		remoteGroups, err := s.ListRuleGroupsForUserAndNamespace(context.Background(), tenantID, "")
+		if err != nil {
+			return errors.Wrap(err, "sync operation unsuccessful, unable to list existing rule groups")
+		}
+		remote := map[string]map[string]rules.RuleGroup{}
+		for _, rg := range remoteGroups {
+			if remote[rg.Namespace] == nil {
+				remote[rg.Namespace] = map[string]rules.RuleGroup{}
+			}
+			remote[rg.Namespace][rg.Name] = rg.RuleGroup
+		}
+
+		for namespace, groups := range tenantNamespaces {
+			remoteByName := remote[namespace]
+
+			for name, group := range groups {
+				curGroup, ok := remoteByName[name]
+				if !ok {
+					changes = append(changes, syncChange{Tenant: tenantID, Namespace: namespace, Group: group, Action: syncActionCreate})
+					continue
+				}
+				if err := rules.CompareGroups(curGroup, group); err != nil {
+					changes = append(changes, syncChange{Tenant: tenantID, Namespace: namespace, Group: group, Action: syncActionUpdate})
+					continue
+				}
+				changes = append(changes, syncChange{Tenant: tenantID, Namespace: namespace, Group: group, Action: syncActionUnchanged})
+			}
+		}
+
+		for namespace, groups := range remote {
+			if !r.inScope(namespace) {
+				continue
+			}
+			localGroups := tenantNamespaces[namespace]
+			for name, rg := range groups {
+				if _, ok := localGroups[name]; !ok {
+					changes = append(changes, syncChange{Tenant: tenantID, Namespace: namespace, Group: rg, Action: syncActionDelete})
+				}
+			}
+		}
+	}
+
+	if r.DryRun {
+		return r.printSyncPlan(changes)
+	}
 
+	if err := r.applySyncPlan(changes); err != nil {
+		return err
+	}
 	ruleLoadSuccessTimestamp.SetToCurrentTime()
 	return nil
 }
+
+func (r *RuleCommand) printSyncPlan(changes []syncChange) error {
+	for _, change := range changes {
+		if change.Action == syncActionUnchanged {
+			continue
+		}
+		fmt.Printf("%s: %s/%s/%s\n", change.Action, change.Tenant, change.Namespace, change.Group.Name)
+		d, err := yaml.Marshal(&change.Group)
+		if err != nil {
+			return err
+		}
+		if err := quick.Highlight(os.Stdout, string(d), "yaml", "terminal", "swapoff"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applySyncPlan executes changes concurrently. Every tenant involved was
+// already resolved once in syncRules, so the concurrent storeForTenant
+// lookups below only ever read the tenant store cache, never populate it.
+func (r *RuleCommand) applySyncPlan(changes []syncChange) error {
+	concurrency := r.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	g, ctx := errgroup.WithContext(context.Background())
+	sem := make(chan struct{}, concurrency)
+
+	for _, change := range changes {
+		change := change
+		if change.Action == syncActionUnchanged {
+			recordRuleLoadAction(change.Namespace, change.Group.Name, syncActionUnchanged)
+			continue
+		}
+
+		sem <- struct{}{}
+		g.Go(func() error {
+			defer func() { <-sem }()
+
+			s, err := r.storeForTenant(change.Tenant)
+			if err != nil {
+				return err
+			}
+
+			switch change.Action {
+			case syncActionCreate, syncActionUpdate:
+				err = s.SetRuleGroup(ctx, change.Tenant, change.Namespace, change.Group)
+			case syncActionDelete:
+				err = s.DeleteRuleGroup(ctx, change.Tenant, change.Namespace, change.Group.Name)
+			}
+			if err != nil {
+				ruleLoadErrorsTotal.WithLabelValues(change.Namespace, change.Group.Name).Inc()
+				return errors.Wrapf(err, "unable to %s group %s/%s/%s", change.Action, change.Tenant, change.Namespace, change.Group.Name)
+			}
+			recordRuleLoadAction(change.Namespace, change.Group.Name, change.Action)
+
+			log.WithFields(log.Fields{
+				"tenant":    change.Tenant,
+				"group":     change.Group.Name,
+				"namespace": change.Namespace,
+				"action":    change.Action.String(),
+			}).Infof("synced rule group")
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+func (r *RuleCommand) lintRules(k *kingpin.ParseContext) error {
+	nss, err := rules.ParseFiles(r.RuleFiles)
+	if err != nil {
+		return errors.Wrap(err, "lint operation unsuccessful, unable to parse rules files")
+	}
+
+	cfg, err := r.lintConfig()
+	if err != nil {
+		return err
+	}
+
+	issues := lint.Lint(nss, cfg)
+
+	switch r.LintFormat {
+	case "json":
+		if err := json.NewEncoder(os.Stdout).Encode(issues); err != nil {
+			return err
+		}
+	default:
+		for _, iss := range issues {
+			fmt.Printf("%s: %s/%s/%s: %s\n", iss.Severity, iss.Namespace, iss.Group, iss.Rule, iss.Message)
+		}
+	}
+
+	for _, iss := range issues {
+		if iss.Severity == lint.SeverityError {
+			return fmt.Errorf("lint operation unsuccessful, errors were found")
+		}
+	}
+	return nil
+}
+
+func (r *RuleCommand) testRules(k *kingpin.ParseContext) error {
+	results, err := ruletest.RunFiles(r.TestFiles)
+	if err != nil {
+		return errors.Wrap(err, "test operation unsuccessful")
+	}
+
+	failed := false
+	for _, result := range results {
+		if result.Passed() {
+			fmt.Printf("PASS: %s\n", result.File)
+			continue
+		}
+		failed = true
+		fmt.Printf("FAIL: %s\n", result.File)
+		for _, f := range result.Failures {
+			fmt.Printf("  %s: %s\n", f.TestGroup, f.Message)
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("test operation unsuccessful, one or more tests failed")
+	}
+	return nil
+}
+
+func (r *RuleCommand) lintConfig() (lint.Config, error) {
+	required := map[string][]string{}
+	for _, raw := range r.RequireLabels {
+		parts := strings.SplitN(raw, "=", 2)
+		if len(parts) != 2 {
+			return lint.Config{}, fmt.Errorf("invalid --require-label %q, expected label=value1|value2", raw)
+		}
+		required[parts[0]] = strings.Split(parts[1], "|")
+	}
+
+	return lint.Config{
+		RequiredLabels:   required,
+		MaxSubqueryDepth: r.MaxSubqueryDepth,
+	}, nil
+}
@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/cortextool/pkg/rules"
+)
+
+// Severity classifies how serious a lint Issue is. Errors fail the lint run;
+// warnings are reported but do not affect the exit code on their own.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a single problem found with a rule.
+type Issue struct {
+	Namespace string   `json:"namespace"`
+	Group     string   `json:"group"`
+	Rule      string   `json:"rule"`
+	Severity  Severity `json:"severity"`
+	Message   string   `json:"message"`
+}
+
+// Config controls which checks Lint performs.
+type Config struct {
+	// RequiredLabels maps a label name to the set of values alerting rules
+	// are allowed to use for it, e.g. {"severity": {"critical", "warning", "info"}}.
+	RequiredLabels map[string][]string
+
+	// MaxSubqueryDepth is the deepest chain of nested subqueries allowed
+	// before a warning is raised. Zero disables the check.
+	MaxSubqueryDepth int
+}
+
+// recordingRuleName matches the "level:metric:operation" convention, e.g.
+// "job:http_requests:rate5m".
+var recordingRuleName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*:[a-zA-Z_][a-zA-Z0-9_]*:[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Lint validates every rule in nss against cfg and returns the issues found.
+func Lint(nss []rules.RuleNamespace, cfg Config) []Issue {
+	var issues []Issue
+
+	for _, ns := range nss {
+		for _, group := range ns.Groups {
+			for _, rule := range group.Rules {
+				issues = append(issues, lintRule(ns.Namespace, group.Name, rule, cfg)...)
+			}
+		}
+	}
+
+	return issues
+}
+
+func lintRule(namespace, group string, rule rules.Rule, cfg Config) []Issue {
+	var issues []Issue
+
+	name := rule.Record
+	if rule.Alert != "" {
+		name = rule.Alert
+	}
+
+	issue := func(severity Severity, format string, args ...interface{}) Issue {
+		return Issue{
+			Namespace: namespace,
+			Group:     group,
+			Rule:      name,
+			Severity:  severity,
+			Message:   fmt.Sprintf(format, args...),
+		}
+	}
+
+	expr, err := parser.ParseExpr(rule.Expr)
+	if err != nil {
+		return append(issues, issue(SeverityError, "invalid PromQL expression: %v", err))
+	}
+
+	if rule.Alert != "" {
+		for label, allowed := range cfg.RequiredLabels {
+			value, ok := rule.Labels[label]
+			if !ok {
+				issues = append(issues, issue(SeverityError, "missing required label %q", label))
+				continue
+			}
+			if !contains(allowed, value) {
+				issues = append(issues, issue(SeverityError, "label %q has value %q, must be one of %s", label, value, strings.Join(allowed, ", ")))
+			}
+		}
+	}
+
+	if rule.Record != "" && !recordingRuleName.MatchString(rule.Record) {
+		issues = append(issues, issue(SeverityError, "recording rule name %q does not follow the level:metric:operation convention", rule.Record))
+	}
+
+	issues = append(issues, lintExpr(expr, rule.Record != "", cfg, issue)...)
+
+	return issues
+}
+
+func lintExpr(expr parser.Expr, isRecordingRule bool, cfg Config, issue func(Severity, string, ...interface{}) Issue) []Issue {
+	var issues []Issue
+
+	parser.Inspect(expr, func(node parser.Node, path []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.Call:
+			// rate()/irate() are not checked for a missing range vector here:
+			// parser.ParseExpr already rejects that at parse time, so any
+			// expression reaching this point is guaranteed to have a valid
+			// range-vector argument.
+			if isRecordingRule && (n.Func.Name == "topk" || n.Func.Name == "bottomk") {
+				issues = append(issues, issue(SeverityWarning, "%s() in a recording rule produces a non-deterministic series set", n.Func.Name))
+			}
+		case *parser.SubqueryExpr:
+			if cfg.MaxSubqueryDepth > 0 && subqueryDepth(path)+1 > cfg.MaxSubqueryDepth {
+				issues = append(issues, issue(SeverityWarning, "subquery nested deeper than %d levels", cfg.MaxSubqueryDepth))
+			}
+		}
+		return nil
+	})
+
+	return issues
+}
+
+func subqueryDepth(path []parser.Node) int {
+	depth := 0
+	for _, n := range path {
+		if _, ok := n.(*parser.SubqueryExpr); ok {
+			depth++
+		}
+	}
+	return depth
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/cortextool/pkg/client"
+	"github.com/grafana/cortextool/pkg/rules"
+)
+
+// cortexStore implements RuleStore against a running Cortex ruler, via the
+// same API the rest of the CLI already talks to.
+type cortexStore struct {
+	cli *client.CortexClient
+}
+
+// NewCortexStore returns a RuleStore backed by a Cortex ruler's HTTP API.
+func NewCortexStore(cli *client.CortexClient) RuleStore {
+	return &cortexStore{cli: cli}
+}
+
+// ListAllUsers is not supported by the Cortex ruler API: a client is only
+// ever authenticated as a single tenant.
+func (s *cortexStore) ListAllUsers(ctx context.Context) ([]string, error) {
+	return nil, fmt.Errorf("listing all tenants is not supported by the cortex backend, use --id-file with a multi-tenant backend instead")
+}
+
+func (s *cortexStore) ListRuleGroupsForUserAndNamespace(ctx context.Context, userID, namespace string) ([]RuleGroup, error) {
+	nss, err := s.cli.ListRules(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []RuleGroup
+	for ns, rgs := range nss {
+		for _, rg := range rgs {
+			groups = append(groups, RuleGroup{RuleGroup: rg, Namespace: ns})
+		}
+	}
+	return groups, nil
+}
+
+func (s *cortexStore) GetRuleGroup(ctx context.Context, userID, namespace, group string) (*rules.RuleGroup, error) {
+	rg, err := s.cli.GetRuleGroup(ctx, namespace, group)
+	if err == client.ErrResourceNotFound {
+		return nil, ErrGroupNotFound
+	}
+	return rg, err
+}
+
+func (s *cortexStore) SetRuleGroup(ctx context.Context, userID, namespace string, group rules.RuleGroup) error {
+	return s.cli.CreateRuleGroup(ctx, namespace, group)
+}
+
+func (s *cortexStore) DeleteRuleGroup(ctx context.Context, userID, namespace, group string) error {
+	return s.cli.DeleteRuleGroup(ctx, namespace, group)
+}
+
+func (s *cortexStore) DeleteNamespace(ctx context.Context, userID, namespace string) error {
+	groups, err := s.ListRuleGroupsForUserAndNamespace(ctx, userID, namespace)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if err := s.DeleteRuleGroup(ctx, userID, namespace, group.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
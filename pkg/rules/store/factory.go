@@ -0,0 +1,74 @@
+package store
+
+import (
+	"io/ioutil"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thanos-io/thanos/pkg/objstore/client"
+
+	cortexclient "github.com/grafana/cortextool/pkg/client"
+)
+
+// Backend identifies which RuleStore implementation to use.
+type Backend string
+
+const (
+	// BackendCortex talks to a running Cortex ruler's HTTP API.
+	BackendCortex Backend = "cortex"
+	// BackendLocal reads and writes rule groups from a local directory.
+	BackendLocal Backend = "local"
+	// BackendObjectStorage reads and writes rule groups from an object
+	// storage bucket (S3, GCS, Azure, ...).
+	BackendObjectStorage Backend = "object-storage"
+)
+
+// Config configures which RuleStore backend to construct.
+type Config struct {
+	Backend Backend
+
+	// Used when Backend == BackendLocal.
+	LocalDirectory string
+
+	// Used when Backend == BackendObjectStorage. ObjectStorageConfigFile
+	// points at a thanos-style bucket config YAML file, the same format
+	// accepted by `thanos bucket` and Cortex's blocks storage.
+	ObjectStorageConfigFile string
+}
+
+// NewRuleStore builds the RuleStore selected by cfg. cortexCli is used for
+// BackendCortex and may be nil otherwise.
+func NewRuleStore(cfg Config, cortexCli *cortexclient.CortexClient) (RuleStore, error) {
+	switch cfg.Backend {
+	case "", BackendCortex:
+		if cortexCli == nil {
+			return nil, errors.New("cortex backend selected but no cortex client is configured")
+		}
+		return NewCortexStore(cortexCli), nil
+
+	case BackendLocal:
+		if cfg.LocalDirectory == "" {
+			return nil, errors.New("--local-rule-dir is required when --backend=local")
+		}
+		return NewLocalStore(cfg.LocalDirectory), nil
+
+	case BackendObjectStorage:
+		if cfg.ObjectStorageConfigFile == "" {
+			return nil, errors.New("--object-storage-config-file is required when --backend=object-storage")
+		}
+		confContentYaml, err := ioutil.ReadFile(cfg.ObjectStorageConfigFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read object storage config file")
+		}
+
+		bucket, err := client.NewBucket(log.NewNopLogger(), confContentYaml, prometheus.DefaultRegisterer, "cortextool-rules")
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to create object storage bucket client")
+		}
+		return NewObjectStore(bucket), nil
+
+	default:
+		return nil, errors.Errorf("unknown rule store backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/grafana/cortextool/pkg/rules"
+)
+
+// localStore implements RuleStore against a directory tree laid out the
+// same way Cortex's own local ruler storage expects: <dir>/<tenant>/<namespace>,
+// where each namespace file is a YAML-encoded set of rule groups.
+type localStore struct {
+	dir string
+}
+
+// NewLocalStore returns a RuleStore rooted at dir.
+func NewLocalStore(dir string) RuleStore {
+	return &localStore{dir: dir}
+}
+
+func (s *localStore) ListAllUsers(ctx context.Context) ([]string, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list tenants")
+	}
+
+	var users []string
+	for _, e := range entries {
+		if e.IsDir() {
+			users = append(users, e.Name())
+		}
+	}
+	return users, nil
+}
+
+func (s *localStore) ListRuleGroupsForUserAndNamespace(ctx context.Context, userID, namespace string) ([]RuleGroup, error) {
+	if namespace != "" {
+		ns, err := s.readNamespace(userID, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return wrapGroups(ns.Namespace, ns.Groups), nil
+	}
+
+	namespaces, err := ioutil.ReadDir(filepath.Join(s.dir, userID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "unable to list namespaces for tenant %s", userID)
+	}
+
+	var groups []RuleGroup
+	for _, f := range namespaces {
+		if f.IsDir() {
+			continue
+		}
+		ns, err := s.readNamespace(userID, f.Name())
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, wrapGroups(ns.Namespace, ns.Groups)...)
+	}
+	return groups, nil
+}
+
+func wrapGroups(namespace string, groups []rules.RuleGroup) []RuleGroup {
+	wrapped := make([]RuleGroup, 0, len(groups))
+	for _, g := range groups {
+		wrapped = append(wrapped, RuleGroup{RuleGroup: g, Namespace: namespace})
+	}
+	return wrapped
+}
+
+func (s *localStore) GetRuleGroup(ctx context.Context, userID, namespace, group string) (*rules.RuleGroup, error) {
+	ns, err := s.readNamespace(userID, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range ns.Groups {
+		if g.Name == group {
+			g := g
+			return &g, nil
+		}
+	}
+	return nil, ErrGroupNotFound
+}
+
+func (s *localStore) SetRuleGroup(ctx context.Context, userID, namespace string, group rules.RuleGroup) error {
+	ns, err := s.readNamespace(userID, namespace)
+	if err != nil && errors.Cause(err) != ErrGroupNotFound {
+		return err
+	}
+	ns.Namespace = namespace
+
+	replaced := false
+	for i, g := range ns.Groups {
+		if g.Name == group.Name {
+			ns.Groups[i] = group
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		ns.Groups = append(ns.Groups, group)
+	}
+
+	return s.writeNamespace(userID, ns)
+}
+
+func (s *localStore) DeleteRuleGroup(ctx context.Context, userID, namespace, group string) error {
+	ns, err := s.readNamespace(userID, namespace)
+	if err != nil {
+		return err
+	}
+
+	groups := ns.Groups[:0]
+	for _, g := range ns.Groups {
+		if g.Name != group {
+			groups = append(groups, g)
+		}
+	}
+	ns.Groups = groups
+
+	if len(ns.Groups) == 0 {
+		return os.Remove(s.namespacePath(userID, namespace))
+	}
+	return s.writeNamespace(userID, ns)
+}
+
+func (s *localStore) DeleteNamespace(ctx context.Context, userID, namespace string) error {
+	err := os.Remove(s.namespacePath(userID, namespace))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *localStore) namespacePath(userID, namespace string) string {
+	return filepath.Join(s.dir, userID, namespace)
+}
+
+func (s *localStore) readNamespace(userID, namespace string) (rules.RuleNamespace, error) {
+	path := s.namespacePath(userID, namespace)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return rules.RuleNamespace{Namespace: namespace}, ErrGroupNotFound
+		}
+		return rules.RuleNamespace{}, errors.Wrapf(err, "unable to stat namespace %s for tenant %s", namespace, userID)
+	}
+
+	nss, err := rules.ParseFiles([]string{path})
+	if err != nil {
+		return rules.RuleNamespace{}, errors.Wrapf(err, "unable to parse namespace %s for tenant %s", namespace, userID)
+	}
+	if len(nss) == 0 {
+		return rules.RuleNamespace{Namespace: namespace}, nil
+	}
+	return nss[0], nil
+}
+
+func (s *localStore) writeNamespace(userID string, ns rules.RuleNamespace) error {
+	d, err := yaml.Marshal(&ns)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(s.dir, userID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "unable to create tenant directory for %s", userID)
+	}
+
+	return ioutil.WriteFile(s.namespacePath(userID, ns.Namespace), d, 0644)
+}
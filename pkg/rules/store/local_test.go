@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/cortextool/pkg/rules"
+)
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalStore(t.TempDir())
+
+	group := rules.RuleGroup{
+		Name: "test-group",
+		Rules: []rules.Rule{
+			{Record: "job:up:sum", Expr: "sum(up)"},
+		},
+	}
+
+	if err := s.SetRuleGroup(ctx, "tenant-a", "ns1", group); err != nil {
+		t.Fatalf("SetRuleGroup: %v", err)
+	}
+
+	got, err := s.GetRuleGroup(ctx, "tenant-a", "ns1", "test-group")
+	if err != nil {
+		t.Fatalf("GetRuleGroup: %v", err)
+	}
+	if got.Name != group.Name {
+		t.Fatalf("GetRuleGroup returned group %q, want %q", got.Name, group.Name)
+	}
+
+	if _, err := s.GetRuleGroup(ctx, "tenant-a", "ns1", "missing-group"); err != ErrGroupNotFound {
+		t.Fatalf("GetRuleGroup for missing group returned %v, want ErrGroupNotFound", err)
+	}
+
+	groups, err := s.ListRuleGroupsForUserAndNamespace(ctx, "tenant-a", "")
+	if err != nil {
+		t.Fatalf("ListRuleGroupsForUserAndNamespace: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Namespace != "ns1" || groups[0].Name != "test-group" {
+		t.Fatalf("ListRuleGroupsForUserAndNamespace returned %+v, want a single ns1/test-group entry", groups)
+	}
+
+	if err := s.DeleteRuleGroup(ctx, "tenant-a", "ns1", "test-group"); err != nil {
+		t.Fatalf("DeleteRuleGroup: %v", err)
+	}
+	if _, err := s.GetRuleGroup(ctx, "tenant-a", "ns1", "test-group"); err != ErrGroupNotFound {
+		t.Fatalf("GetRuleGroup after delete returned %v, want ErrGroupNotFound", err)
+	}
+}
+
+func TestLocalStoreDeleteNamespaceOnEmptyDir(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalStore(t.TempDir())
+
+	if err := s.DeleteNamespace(ctx, "tenant-a", "does-not-exist"); err != nil {
+		t.Fatalf("DeleteNamespace on a namespace that never existed should be a no-op, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"gopkg.in/yaml.v2"
+
+	"github.com/grafana/cortextool/pkg/rules"
+)
+
+// objectStore implements RuleStore on top of a thanos objstore.Bucket, so
+// the same code path works for S3, GCS and Azure blob storage. Objects are
+// keyed as rules/<tenant>/<base64(namespace)>/<base64(group)> and contain a
+// YAML-encoded rules.RuleGroup.
+type objectStore struct {
+	bucket objstore.Bucket
+}
+
+// NewObjectStore returns a RuleStore backed by the given bucket.
+func NewObjectStore(bucket objstore.Bucket) RuleStore {
+	return &objectStore{bucket: bucket}
+}
+
+const objectStorePrefix = "rules/"
+
+func encodeKeyPart(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func decodeKeyPart(s string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	return string(b), err
+}
+
+func groupObjectKey(userID, namespace, group string) string {
+	return objectStorePrefix + userID + "/" + encodeKeyPart(namespace) + "/" + encodeKeyPart(group)
+}
+
+func (s *objectStore) ListAllUsers(ctx context.Context) ([]string, error) {
+	var users []string
+	err := s.bucket.Iter(ctx, objectStorePrefix, func(name string) error {
+		user := strings.TrimSuffix(strings.TrimPrefix(name, objectStorePrefix), objstore.DirDelim)
+		users = append(users, user)
+		return nil
+	})
+	return users, errors.Wrap(err, "unable to list tenants in object storage")
+}
+
+func (s *objectStore) ListRuleGroupsForUserAndNamespace(ctx context.Context, userID, namespace string) ([]RuleGroup, error) {
+	var groups []RuleGroup
+
+	visit := func(namespaceKey string) error {
+		ns, err := decodeKeyPart(strings.TrimSuffix(strings.TrimPrefix(namespaceKey, objectStorePrefix+userID+"/"), objstore.DirDelim))
+		if err != nil {
+			return errors.Wrap(err, "unable to decode namespace key")
+		}
+		if namespace != "" && ns != namespace {
+			return nil
+		}
+
+		return s.bucket.Iter(ctx, namespaceKey, func(groupKey string) error {
+			group, err := s.getGroupAtKey(ctx, groupKey)
+			if err != nil {
+				return err
+			}
+			groups = append(groups, RuleGroup{RuleGroup: *group, Namespace: ns})
+			return nil
+		})
+	}
+
+	err := s.bucket.Iter(ctx, objectStorePrefix+userID+"/", visit)
+	return groups, errors.Wrap(err, "unable to list rule groups in object storage")
+}
+
+func (s *objectStore) GetRuleGroup(ctx context.Context, userID, namespace, group string) (*rules.RuleGroup, error) {
+	return s.getGroupAtKey(ctx, groupObjectKey(userID, namespace, group))
+}
+
+func (s *objectStore) getGroupAtKey(ctx context.Context, key string) (*rules.RuleGroup, error) {
+	exists, err := s.bucket.Exists(ctx, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to check object existence")
+	}
+	if !exists {
+		return nil, ErrGroupNotFound
+	}
+
+	r, err := s.bucket.Get(ctx, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to fetch rule group from object storage")
+	}
+	defer r.Close()
+
+	d, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read rule group from object storage")
+	}
+
+	var group rules.RuleGroup
+	if err := yaml.Unmarshal(d, &group); err != nil {
+		return nil, errors.Wrap(err, "unable to decode rule group from object storage")
+	}
+	return &group, nil
+}
+
+func (s *objectStore) SetRuleGroup(ctx context.Context, userID, namespace string, group rules.RuleGroup) error {
+	d, err := yaml.Marshal(&group)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode rule group")
+	}
+
+	key := groupObjectKey(userID, namespace, group.Name)
+	return errors.Wrap(s.bucket.Upload(ctx, key, bytes.NewReader(d)), "unable to upload rule group to object storage")
+}
+
+func (s *objectStore) DeleteRuleGroup(ctx context.Context, userID, namespace, group string) error {
+	key := groupObjectKey(userID, namespace, group)
+	exists, err := s.bucket.Exists(ctx, key)
+	if err != nil {
+		return errors.Wrap(err, "unable to check object existence")
+	}
+	if !exists {
+		return ErrGroupNotFound
+	}
+	return errors.Wrap(s.bucket.Delete(ctx, key), "unable to delete rule group from object storage")
+}
+
+func (s *objectStore) DeleteNamespace(ctx context.Context, userID, namespace string) error {
+	groups, err := s.ListRuleGroupsForUserAndNamespace(ctx, userID, namespace)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if err := s.DeleteRuleGroup(ctx, userID, namespace, group.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/cortextool/pkg/rules"
+)
+
+// ErrGroupNotFound is returned by GetRuleGroup when no such group exists.
+var ErrGroupNotFound = errors.New("rule group not found")
+
+// RuleGroup pairs a parsed rule group with the namespace it lives in, since
+// stores may list across namespaces in a single call.
+type RuleGroup struct {
+	rules.RuleGroup
+	Namespace string
+}
+
+// RuleStore abstracts the persistence layer for rule groups so that
+// RuleCommand can operate against a running Cortex ruler, a local
+// directory of rule files, or an object storage bucket interchangeably.
+type RuleStore interface {
+	// ListAllUsers returns the tenant IDs known to this store.
+	ListAllUsers(ctx context.Context) ([]string, error)
+
+	// ListRuleGroupsForUserAndNamespace returns the rule groups configured
+	// for the given tenant and namespace. An empty namespace lists groups
+	// across all namespaces for the tenant.
+	ListRuleGroupsForUserAndNamespace(ctx context.Context, userID, namespace string) ([]RuleGroup, error)
+
+	// GetRuleGroup returns a single rule group, or ErrGroupNotFound if it
+	// does not exist.
+	GetRuleGroup(ctx context.Context, userID, namespace, group string) (*rules.RuleGroup, error)
+
+	// SetRuleGroup creates or replaces a rule group.
+	SetRuleGroup(ctx context.Context, userID, namespace string, group rules.RuleGroup) error
+
+	// DeleteRuleGroup removes a single rule group.
+	DeleteRuleGroup(ctx context.Context, userID, namespace, group string) error
+
+	// DeleteNamespace removes every rule group in a namespace.
+	DeleteNamespace(ctx context.Context, userID, namespace string) error
+}
@@ -0,0 +1,373 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	promrules "github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"gopkg.in/yaml.v2"
+
+	"github.com/grafana/cortextool/pkg/rules"
+)
+
+// Result is the outcome of running a single test file.
+type Result struct {
+	File     string
+	Failures []Failure
+}
+
+// Failure describes a single assertion that did not hold.
+type Failure struct {
+	TestGroup string
+	Message   string
+}
+
+// Passed reports whether a Result recorded no failures.
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// RunFiles runs every `rules test` input file and returns one Result per
+// file. It never returns an error for test failures, only for problems
+// loading or parsing the files themselves; check Result.Passed() for the
+// former.
+func RunFiles(files []string) ([]Result, error) {
+	results := make([]Result, 0, len(files))
+	for _, f := range files {
+		result, err := runFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to run test file %s", f)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func runFile(path string) (Result, error) {
+	result := Result{File: path}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return result, err
+	}
+
+	var tf File
+	if err := yaml.UnmarshalStrict(raw, &tf); err != nil {
+		return result, errors.Wrap(err, "unable to parse test file")
+	}
+
+	dir := filepath.Dir(path)
+	ruleFiles := make([]string, 0, len(tf.RuleFiles))
+	for _, rf := range tf.RuleFiles {
+		if !filepath.IsAbs(rf) {
+			rf = filepath.Join(dir, rf)
+		}
+		ruleFiles = append(ruleFiles, rf)
+	}
+
+	nss, err := rules.ParseFiles(ruleFiles)
+	if err != nil {
+		return result, errors.Wrap(err, "unable to parse referenced rule files")
+	}
+
+	for _, tg := range tf.Tests {
+		failures, err := runTestGroup(tg, nss, time.Duration(tf.EvaluationInterval))
+		if err != nil {
+			return result, errors.Wrapf(err, "test group %q", tg.Name)
+		}
+		result.Failures = append(result.Failures, failures...)
+	}
+
+	return result, nil
+}
+
+func runTestGroup(tg TestGroup, nss []rules.RuleNamespace, defaultInterval time.Duration) ([]Failure, error) {
+	interval := time.Duration(tg.Interval)
+	if interval == 0 {
+		interval = defaultInterval
+	}
+	if interval == 0 {
+		interval = time.Minute
+	}
+
+	st := newMemStorage()
+	maxEvalTime := time.Duration(0)
+
+	for _, is := range tg.InputSeries {
+		lbls, vals, err := parser.ParseSeriesDesc(is.Series + " " + is.Values)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse input series %q", is.Series)
+		}
+		for i, v := range vals {
+			if v.Omitted {
+				continue
+			}
+			ts := time.Duration(i) * interval
+			st.Append(lbls, ts.Milliseconds(), v.Value)
+			if ts > maxEvalTime {
+				maxEvalTime = ts
+			}
+		}
+	}
+
+	// exprTestsByTime/alertTestsByTime group assertions by the tick they are
+	// due at, so the eval loop below can check each as soon as the group has
+	// been evaluated up to that point in time, rather than only at the end
+	// of the timeline. This matters for alert assertions in particular,
+	// since promrules.AlertingRule only tracks its *current* active alerts -
+	// checking after the full timeline has run would see the rule's state
+	// as of the last tick, not as of the assertion's eval_time.
+	exprTestsByTime := map[time.Duration][]PromqlExprTest{}
+	for _, et := range tg.PromqlExprTests {
+		t := time.Duration(et.EvalTime)
+		exprTestsByTime[t] = append(exprTestsByTime[t], et)
+		if t > maxEvalTime {
+			maxEvalTime = t
+		}
+	}
+	alertTestsByTime := map[time.Duration][]AlertRuleTest{}
+	for _, at := range tg.AlertRuleTests {
+		t := time.Duration(at.EvalTime)
+		alertTestsByTime[t] = append(alertTestsByTime[t], at)
+		if t > maxEvalTime {
+			maxEvalTime = t
+		}
+	}
+
+	engine := promql.NewEngine(promql.EngineOpts{
+		MaxSamples:    50000000,
+		Timeout:       time.Minute,
+		LookbackDelta: 5 * time.Minute,
+	})
+
+	groups, err := buildGroups(nss, st, engine, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []Failure
+	ctx := context.Background()
+	for ts := time.Duration(0); ts <= maxEvalTime; ts += interval {
+		for _, g := range groups {
+			g.Eval(ctx, time.Unix(0, 0).Add(ts))
+		}
+
+		for _, et := range exprTestsByTime[ts] {
+			fs, err := checkExprTest(ctx, engine, st, et)
+			if err != nil {
+				return nil, err
+			}
+			failures = append(failures, fs...)
+		}
+		for _, at := range alertTestsByTime[ts] {
+			failures = append(failures, checkAlertTest(groups, at)...)
+		}
+	}
+
+	for i := range failures {
+		failures[i].TestGroup = tg.Name
+	}
+	sortFailures(failures)
+	return failures, nil
+}
+
+func buildGroups(nss []rules.RuleNamespace, st *memStorage, engine *promql.Engine, interval time.Duration) ([]*promrules.Group, error) {
+	opts := &promrules.ManagerOptions{
+		Queryable:  st,
+		QueryFunc:  promrules.EngineQueryFunc(engine, st),
+		Appendable: storageAppendable{st: st},
+		Context:    context.Background(),
+		Logger:     log.NewNopLogger(),
+	}
+
+	var groups []*promrules.Group
+	for _, ns := range nss {
+		for _, group := range ns.Groups {
+			promRules := make([]promrules.Rule, 0, len(group.Rules))
+			for _, rule := range group.Rules {
+				expr, err := parser.ParseExpr(rule.Expr)
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid expression in group %s", group.Name)
+				}
+
+				if rule.Alert != "" {
+					promRules = append(promRules, promrules.NewAlertingRule(
+						rule.Alert,
+						expr,
+						parseForDuration(rule.For),
+						labels.FromMap(rule.Labels),
+						labels.FromMap(rule.Annotations),
+						nil,
+						"",
+						false,
+						log.NewNopLogger(),
+					))
+					continue
+				}
+				promRules = append(promRules, promrules.NewRecordingRule(rule.Record, expr, labels.FromMap(rule.Labels)))
+			}
+
+			groups = append(groups, promrules.NewGroup(promrules.GroupOptions{
+				Name:     group.Name,
+				Interval: interval,
+				Rules:    promRules,
+				Opts:     opts,
+			}))
+		}
+	}
+	return groups, nil
+}
+
+func parseForDuration(s string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	d, err := model.ParseDuration(s)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(d)
+}
+
+// storageAppendable feeds samples produced by rule evaluation back into st,
+// the same memStorage used as the group's Queryable - mirroring how
+// Prometheus's own rule manager writes evaluated samples back into its TSDB.
+// Without this, a promql_expr_test reading a recording rule's output metric
+// would always see an empty vector.
+type storageAppendable struct {
+	st *memStorage
+}
+
+func (a storageAppendable) Appender(ctx context.Context) storage.Appender {
+	return storageAppender{st: a.st}
+}
+
+type storageAppender struct {
+	st *memStorage
+}
+
+func (a storageAppender) Append(ref uint64, lbls labels.Labels, t int64, v float64) (uint64, error) {
+	a.st.Append(lbls, t, v)
+	return ref, nil
+}
+
+func (a storageAppender) Commit() error   { return nil }
+func (a storageAppender) Rollback() error { return nil }
+
+func checkExprTest(ctx context.Context, engine *promql.Engine, st *memStorage, et PromqlExprTest) ([]Failure, error) {
+	q, err := engine.NewInstantQuery(st, et.Expr, time.Unix(0, 0).Add(time.Duration(et.EvalTime)))
+	if err != nil {
+		return []Failure{{Message: fmt.Sprintf("expr %q: %v", et.Expr, err)}}, nil
+	}
+	res := q.Exec(ctx)
+	if res.Err != nil {
+		return []Failure{{Message: fmt.Sprintf("expr %q: %v", et.Expr, res.Err)}}, nil
+	}
+
+	vec, err := res.Vector()
+	if err != nil {
+		return []Failure{{Message: fmt.Sprintf("expr %q: result is not an instant vector: %v", et.Expr, err)}}, nil
+	}
+
+	var failures []Failure
+	seen := make([]bool, len(et.ExpSamples))
+	for _, sample := range vec {
+		matched := false
+		for i, exp := range et.ExpSamples {
+			lbls, err := parser.ParseMetric(exp.Labels)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to parse expected labels %q", exp.Labels)
+			}
+			if labels.Equal(lbls, sample.Metric) {
+				seen[i] = true
+				matched = true
+				if sample.V != exp.Value {
+					failures = append(failures, Failure{Message: fmt.Sprintf("expr %q: for labels %s, expected value %v, got %v", et.Expr, exp.Labels, exp.Value, sample.V)})
+				}
+			}
+		}
+		if !matched {
+			failures = append(failures, Failure{Message: fmt.Sprintf("expr %q: unexpected output series %s", et.Expr, sample.Metric)})
+		}
+	}
+	for i, ok := range seen {
+		if !ok {
+			failures = append(failures, Failure{Message: fmt.Sprintf("expr %q: expected series with labels %q not found", et.Expr, et.ExpSamples[i].Labels)})
+		}
+	}
+
+	return failures, nil
+}
+
+func checkAlertTest(groups []*promrules.Group, at AlertRuleTest) []Failure {
+	var rule *promrules.AlertingRule
+	for _, g := range groups {
+		for _, r := range g.Rules() {
+			if ar, ok := r.(*promrules.AlertingRule); ok && ar.Name() == at.Alertname {
+				rule = ar
+			}
+		}
+	}
+	if rule == nil {
+		return []Failure{{Message: fmt.Sprintf("alert %q: no such alerting rule", at.Alertname)}}
+	}
+
+	evalTime := time.Unix(0, 0).Add(time.Duration(at.EvalTime))
+
+	var firing []labels.Labels
+	for _, alert := range rule.ActiveAlerts() {
+		if alert.State != promrules.StateFiring {
+			continue
+		}
+		if alert.ActiveAt.After(evalTime) {
+			continue
+		}
+		firing = append(firing, alert.Labels)
+	}
+
+	var failures []Failure
+	matched := make([]bool, len(at.ExpAlerts))
+	for _, lbls := range firing {
+		ok := false
+		for i, exp := range at.ExpAlerts {
+			if labelsContain(lbls, exp.ExpLabels) {
+				matched[i] = true
+				ok = true
+			}
+		}
+		if !ok {
+			failures = append(failures, Failure{Message: fmt.Sprintf("alert %q: unexpected firing alert with labels %s", at.Alertname, lbls)})
+		}
+	}
+	for i, ok := range matched {
+		if !ok {
+			failures = append(failures, Failure{Message: fmt.Sprintf("alert %q: expected alert with labels %v not firing at %s", at.Alertname, at.ExpAlerts[i].ExpLabels, evalTime)})
+		}
+	}
+
+	return failures
+}
+
+func labelsContain(lbls labels.Labels, expected map[string]string) bool {
+	for k, v := range expected {
+		if lbls.Get(k) != v {
+			return false
+		}
+	}
+	return true
+}
+
+func sortFailures(failures []Failure) {
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Message < failures[j].Message })
+}
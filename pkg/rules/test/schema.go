@@ -0,0 +1,59 @@
+package test
+
+import (
+	"github.com/prometheus/common/model"
+)
+
+// File is the schema of a single `rules test` input file, matching the
+// format `promtool test rules` uses.
+type File struct {
+	RuleFiles          []string       `yaml:"rule_files"`
+	EvaluationInterval model.Duration `yaml:"evaluation_interval"`
+	Tests              []TestGroup    `yaml:"tests"`
+}
+
+// TestGroup is one `tests[]` entry: a set of input series evaluated over
+// time, checked against expected instant-vector and alert output.
+type TestGroup struct {
+	Name            string            `yaml:"name"`
+	Interval        model.Duration    `yaml:"interval"`
+	InputSeries     []InputSeries     `yaml:"input_series"`
+	AlertRuleTests  []AlertRuleTest   `yaml:"alert_rule_test"`
+	PromqlExprTests []PromqlExprTest  `yaml:"promql_expr_test"`
+	ExternalLabels  map[string]string `yaml:"external_labels"`
+}
+
+// InputSeries is a single series' worth of synthetic samples, in promtool's
+// `<metric>{labels} <values>` series-description syntax.
+type InputSeries struct {
+	Series string `yaml:"series"`
+	Values string `yaml:"values"`
+}
+
+// PromqlExprTest asserts the result of evaluating an arbitrary PromQL
+// expression at a point in time.
+type PromqlExprTest struct {
+	Expr       string         `yaml:"expr"`
+	EvalTime   model.Duration `yaml:"eval_time"`
+	ExpSamples []ExpSample    `yaml:"exp_samples"`
+}
+
+// ExpSample is one expected output sample of a PromqlExprTest.
+type ExpSample struct {
+	Labels string  `yaml:"labels"`
+	Value  float64 `yaml:"value"`
+}
+
+// AlertRuleTest asserts which alerts a named alerting rule has fired at a
+// point in time, honouring the rule's `for:` duration.
+type AlertRuleTest struct {
+	EvalTime  model.Duration `yaml:"eval_time"`
+	Alertname string         `yaml:"alertname"`
+	ExpAlerts []ExpAlert     `yaml:"exp_alerts"`
+}
+
+// ExpAlert is one expected firing alert of an AlertRuleTest.
+type ExpAlert struct {
+	ExpLabels      map[string]string `yaml:"exp_labels"`
+	ExpAnnotations map[string]string `yaml:"exp_annotations"`
+}
@@ -0,0 +1,182 @@
+package test
+
+import (
+	"context"
+	"sort"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// memSample is a single (timestamp, value) pair appended to a series.
+type memSample struct {
+	t int64
+	v float64
+}
+
+// memStorage is a minimal, append-only storage.Queryable backed by an
+// in-memory map of label sets to samples. It exists purely to feed
+// synthetic `input_series` values into the PromQL engine during `rules
+// test`; it is not safe for concurrent writes.
+type memStorage struct {
+	series map[string]*memSeries
+}
+
+type memSeries struct {
+	lbls    labels.Labels
+	samples []memSample
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{series: map[string]*memSeries{}}
+}
+
+// Append adds a sample to the series identified by lbls, keeping samples in
+// timestamp order.
+func (s *memStorage) Append(lbls labels.Labels, t int64, v float64) {
+	key := lbls.String()
+	series, ok := s.series[key]
+	if !ok {
+		series = &memSeries{lbls: lbls}
+		s.series[key] = series
+	}
+	i := sort.Search(len(series.samples), func(i int) bool { return series.samples[i].t > t })
+	series.samples = append(series.samples, memSample{})
+	copy(series.samples[i+1:], series.samples[i:])
+	series.samples[i] = memSample{t: t, v: v}
+}
+
+func (s *memStorage) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+	return &memQuerier{storage: s, mint: mint, maxt: maxt}, nil
+}
+
+type memQuerier struct {
+	storage    *memStorage
+	mint, maxt int64
+}
+
+func (q *memQuerier) Select(sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	var matched []storage.Series
+	for _, series := range q.storage.series {
+		if !matchesAll(series.lbls, matchers) {
+			continue
+		}
+		matched = append(matched, &memStorageSeries{series: series, mint: q.mint, maxt: q.maxt})
+	}
+
+	if sortSeries {
+		sort.Slice(matched, func(i, j int) bool {
+			return labels.Compare(matched[i].Labels(), matched[j].Labels()) < 0
+		})
+	}
+
+	return &memSeriesSet{series: matched}
+}
+
+func (q *memQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	values := map[string]struct{}{}
+	for _, series := range q.storage.series {
+		if v := series.lbls.Get(name); v != "" {
+			values[v] = struct{}{}
+		}
+	}
+	out := make([]string, 0, len(values))
+	for v := range values {
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out, nil, nil
+}
+
+func (q *memQuerier) LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	names := map[string]struct{}{}
+	for _, series := range q.storage.series {
+		for _, l := range series.lbls {
+			names[l.Name] = struct{}{}
+		}
+	}
+	out := make([]string, 0, len(names))
+	for n := range names {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out, nil, nil
+}
+
+func (q *memQuerier) Close() error { return nil }
+
+func matchesAll(lbls labels.Labels, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+type memSeriesSet struct {
+	series []storage.Series
+	cur    int
+}
+
+func (s *memSeriesSet) Next() bool {
+	s.cur++
+	return s.cur <= len(s.series)
+}
+
+func (s *memSeriesSet) At() storage.Series {
+	return s.series[s.cur-1]
+}
+
+func (s *memSeriesSet) Err() error                 { return nil }
+func (s *memSeriesSet) Warnings() storage.Warnings { return nil }
+
+type memStorageSeries struct {
+	series     *memSeries
+	mint, maxt int64
+}
+
+func (s *memStorageSeries) Labels() labels.Labels {
+	return s.series.lbls
+}
+
+func (s *memStorageSeries) Iterator() storage.SeriesIterator {
+	var samples []memSample
+	for _, sample := range s.series.samples {
+		if sample.t >= s.mint && sample.t <= s.maxt {
+			samples = append(samples, sample)
+		}
+	}
+	return &memSeriesIterator{samples: samples, cur: -1}
+}
+
+type memSeriesIterator struct {
+	samples []memSample
+	cur     int
+}
+
+func (it *memSeriesIterator) Seek(t int64) bool {
+	for it.cur < len(it.samples)-1 {
+		if it.samples[it.cur+1].t >= t {
+			it.cur++
+			return true
+		}
+		it.cur++
+	}
+	return it.cur >= 0 && it.cur < len(it.samples) && it.samples[it.cur].t >= t
+}
+
+func (it *memSeriesIterator) At() (int64, float64) {
+	s := it.samples[it.cur]
+	return s.t, s.v
+}
+
+func (it *memSeriesIterator) Next() bool {
+	if it.cur+1 >= len(it.samples) {
+		return false
+	}
+	it.cur++
+	return true
+}
+
+func (it *memSeriesIterator) Err() error { return nil }
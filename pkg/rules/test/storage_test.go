@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestMemSeriesIteratorSeek(t *testing.T) {
+	it := &memSeriesIterator{
+		samples: []memSample{{t: 0, v: 1}, {t: 60000, v: 2}},
+		cur:     -1,
+	}
+
+	if ok := it.Seek(0); !ok {
+		t.Fatalf("Seek(0) = false, want true")
+	}
+	if ts, v := it.At(); ts != 0 || v != 1 {
+		t.Fatalf("At() = (%d, %v), want (0, 1)", ts, v)
+	}
+
+	if ok := it.Seek(60000); !ok {
+		t.Fatalf("Seek(60000) = false, want true")
+	}
+	if ts, v := it.At(); ts != 60000 || v != 2 {
+		t.Fatalf("At() = (%d, %v), want (60000, 2)", ts, v)
+	}
+
+	if ok := it.Seek(120000); ok {
+		ts, v := it.At()
+		t.Fatalf("Seek(120000) = true with stale sample (%d, %v), want false: no sample exists at or after t=120000", ts, v)
+	}
+}
+
+func TestMemSeriesIteratorNext(t *testing.T) {
+	it := &memSeriesIterator{
+		samples: []memSample{{t: 0, v: 1}, {t: 60000, v: 2}},
+		cur:     -1,
+	}
+
+	var got []int64
+	for it.Next() {
+		ts, _ := it.At()
+		got = append(got, ts)
+	}
+	if len(got) != 2 || got[0] != 0 || got[1] != 60000 {
+		t.Fatalf("Next() visited %v, want [0 60000]", got)
+	}
+}
+
+func TestMemStorageAppendAndSelect(t *testing.T) {
+	st := newMemStorage()
+	lbls := labels.FromStrings("__name__", "up")
+	st.Append(lbls, 0, 1)
+	st.Append(lbls, 60000, 0)
+
+	q, err := st.Querier(context.Background(), 0, 60000)
+	if err != nil {
+		t.Fatalf("Querier: %v", err)
+	}
+	defer q.Close()
+
+	set := q.Select(true, nil)
+	if !set.Next() {
+		t.Fatalf("Select returned no series")
+	}
+	series := set.At()
+	if !labels.Equal(series.Labels(), lbls) {
+		t.Fatalf("Select returned labels %v, want %v", series.Labels(), lbls)
+	}
+	if set.Next() {
+		t.Fatalf("Select returned more than one series")
+	}
+}